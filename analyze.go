@@ -0,0 +1,51 @@
+package main
+
+import (
+	"chip8analyze"
+	"chip8mem"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// analyze ROMs without running them and report the problems found
+func analyze(args []string) int {
+	flags := flag.NewFlagSet("analyze", flag.ExitOnError)
+	quiet := flags.Bool("q", false, "only report errors")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: analyze [flags] ROM ...")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	status := 0
+	for _, fname := range flags.Args() {
+		data, err := ioutil.ReadFile(fname)
+		if err != nil {
+			fmt.Println("[!] Error when loading ROM: ", err)
+			status = 1
+			continue
+		}
+		if len(data) > chip8mem.MEMSIZE-chip8mem.MEMSTART {
+			data = data[:chip8mem.MEMSIZE-chip8mem.MEMSTART]
+		}
+		report := chip8analyze.Analyze(data)
+
+		fmt.Printf("[>] %s: %d reachable instructions, platform %s (%s)\n", fname, report.Reachable,
+			report.Platform, strings.Join(report.Reasons, ", "))
+		for _, finding := range report.Findings {
+			if finding.Severity == chip8analyze.ERROR {
+				status = 1
+			} else if *quiet {
+				continue
+			}
+			fmt.Printf("    0x%03X  %-7s  %s\n", finding.Addr, finding.Severity, finding.Message)
+		}
+	}
+	return status
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"chip8cpu"
+	"chip8emu"
+	"flag"
+	"fmt"
+	"time"
+)
+
+// run a ROM uncapped and print its speed, the benchmarks of the packages run with go test
+func bench(args []string) int {
+	flags := flag.NewFlagSet("bench", flag.ExitOnError)
+	rom := flags.String("ROM", "", "run this ROM headless as fast as possible")
+	frames := flags.Int("frames", 3600, "frames to run the ROM for")
+	ipf := flags.Int("ipf", chip8emu.DEFAULTIPF, "instructions per frame for the ROM")
+	cache := flags.Bool("cache", false, "run the ROM through the decoded block cache")
+	vip := flags.Bool("vip", false, "run the ROM by COSMAC VIP machine cycles, ignores -ipf")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: bench -ROM game.ch8 [flags]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *rom != "" {
+		return benchROM(*rom, *frames, *ipf, *cache, *vip)
+	}
+	fmt.Println("[!] No ROM given, run the benchmarks of the packages with go test -bench . ./vendor/chip8cpu ./vendor/chip8video ./vendor/chip8mem")
+	flags.Usage()
+	return 2
+}
+
+// run a ROM for frames without rendering or waiting and print the instructions per second
+func benchROM(rom string, frames int, ipf int, cache bool, vip bool) int {
+	cpu := chip8cpu.CreateHeadlessCpu()
+	cpu.VIPTiming = vip
+	if cache {
+		chip8cpu.EnableBlockCache(cpu)
+	}
+	emu := chip8emu.CreateEmulator(cpu)
+	emu.Ipf = ipf
+	emu.IpfFixed = true
+	if err := chip8emu.LoadROM(emu, rom); err != nil {
+		fmt.Printf("[!] %s\n", err)
+		return 1
+	}
+
+	start := time.Now()
+	err := chip8emu.RunFrames(emu, frames)
+	elapsed := time.Since(start)
+	fmt.Printf("[>] %d instructions in %d frames in %s, %.2f MIPS, %.0f frames/s\n", cpu.Executed, emu.Frames, elapsed,
+		float64(cpu.Executed)/elapsed.Seconds()/1e6, float64(emu.Frames)/elapsed.Seconds())
+	if err != nil {
+		fmt.Printf("[!] %s\n", err)
+		return 1
+	}
+	return 0
+}
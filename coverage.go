@@ -0,0 +1,68 @@
+package main
+
+import (
+	"chip8cover"
+	"chip8mem"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// report the coverage of a ROM from one or more coverage files written with -coverage
+func coverage(args []string) int {
+	flags := flag.NewFlagSet("coverage", flag.ExitOnError)
+	rom := flags.String("ROM", "", "ROM the coverage was recorded for")
+	out := flags.String("o", "", "write the report to this file instead of stdout")
+	htmlOut := flags.String("html", "", "also write the listing as HTML to this file")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: coverage -ROM file [flags] coverage-file ..., the files are merged")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if *rom == "" || flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	data, err := ioutil.ReadFile(*rom)
+	if err != nil {
+		fmt.Println("[!] Error when loading ROM: ", err)
+		return 1
+	}
+	if len(data) > chip8mem.MEMSIZE-chip8mem.MEMSTART {
+		data = data[:chip8mem.MEMSIZE-chip8mem.MEMSTART]
+	}
+	merged := new(chip8mem.Coverage)
+	for _, fname := range flags.Args() {
+		cov, err := chip8mem.LoadCoverage(fname)
+		if err != nil {
+			fmt.Println("[!] Error when loading coverage: ", err)
+			return 1
+		}
+		chip8mem.MergeCoverage(merged, cov)
+	}
+	lines := chip8cover.Listing(merged, data)
+
+	w := os.Stdout
+	if *out != "" {
+		if w, err = os.Create(*out); err != nil {
+			fmt.Println("[!] Error when writing report: ", err)
+			return 1
+		}
+		defer w.Close()
+	}
+	chip8cover.WriteReport(w, lines)
+
+	if *htmlOut != "" {
+		file, err := os.Create(*htmlOut)
+		if err != nil {
+			fmt.Println("[!] Error when writing HTML: ", err)
+			return 1
+		}
+		chip8cover.WriteHTML(file, filepath.Base(*rom), lines)
+		file.Close()
+	}
+	return 0
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"chip8cpu"
+	"chip8dap"
+	"chip8emu"
+	"chip8video"
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+)
+
+// serve the Debug Adapter Protocol for a single debugging session, over stdio unless -listen is given
+// the ROM to run comes from the launch request of the client
+func dap(args []string) int {
+	flags := flag.NewFlagSet("dap", flag.ExitOnError)
+	listen := flags.String("listen", "", "accept a single client on this address, like localhost:4711, instead of using stdio")
+	flags.Parse(args)
+
+	// the protocol owns stdout, everything the emulator prints goes to stderr
+	out := os.Stdout
+	os.Stdout = os.Stderr
+	var session *chip8dap.Session
+	if *listen != "" {
+		listener, err := net.Listen("tcp", *listen)
+		if err != nil {
+			fmt.Println("[!] Error when starting DAP server: ", err)
+			return 1
+		}
+		fmt.Println("[>] Waiting for DAP client on", listener.Addr())
+		conn, err := listener.Accept()
+		listener.Close()
+		if err != nil {
+			fmt.Println("[!] Error when accepting DAP client: ", err)
+			return 1
+		}
+		defer conn.Close()
+		session = chip8dap.CreateSession(conn, conn)
+	} else {
+		session = chip8dap.CreateSession(os.Stdin, out)
+	}
+	go chip8dap.Serve(session)
+
+	var launch chip8dap.LaunchArgs
+	select {
+	case launch = <-session.Launch:
+	case <-session.Done:
+		return 0
+	}
+
+	if err := chip8video.Init(); err != nil {
+		fmt.Println("[!] Error when initializing SDL: ", err)
+		return 1
+	}
+	defer chip8video.Quit()
+	cpu := chip8cpu.CreateCpu()
+	defer chip8video.CloseVideo(cpu.Video)
+	emu := chip8emu.CreateEmulator(cpu)
+	if launch.Ipf > 0 {
+		emu.Ipf = launch.Ipf
+		emu.IpfFixed = true
+	}
+	err := chip8emu.LoadROM(emu, launch.Program)
+	chip8dap.Launched(session, emu, err)
+	if err != nil {
+		<-session.Done
+		return 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-session.Done
+		cancel()
+	}()
+	err = chip8emu.Run(emu, ctx)
+	chip8dap.Exited(session, err)
+	fmt.Printf("[>] Emulator stopped (%s) after %d frames at PC 0x%X\n", err, emu.Frames, cpu.Mem.PC)
+	return 0
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"chip8golden"
+	"chip8video"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// run the scripted golden framebuffer tests
+func golden(args []string) int {
+	flags := flag.NewFlagSet("golden", flag.ExitOnError)
+	update := flags.Bool("update", false, "write the framebuffers at the checkpoints as goldens instead of comparing")
+	out := flags.String("out", "golden-out", "directory for the actual and diff images of failed checkpoints")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: golden [flags] [spec.json ...], default all specs in testdata/golden")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	specs := flags.Args()
+	if len(specs) == 0 {
+		specs, _ = filepath.Glob(filepath.Join("testdata", "golden", "*.json"))
+	}
+
+	passed, failed := 0, 0
+	for _, fname := range specs {
+		spec, err := chip8golden.LoadSpec(fname)
+		if err != nil {
+			fmt.Printf("FAIL %s: %s\n", fname, err)
+			failed++
+			continue
+		}
+		results, err := chip8golden.Run(spec, *update)
+		for _, result := range results {
+			name := fmt.Sprintf("%s@%d", spec.Name, result.Checkpoint.Frame)
+			switch {
+			case result.Err != nil:
+				fmt.Printf("FAIL %s: %s\n", name, result.Err)
+				failed++
+			case result.Updated:
+				fmt.Printf("NEW  %s: %s\n", name, result.Golden)
+				passed++
+			case result.Diff != 0:
+				diff := writeGoldenDiff(*out, name, result)
+				fmt.Printf("FAIL %s: %d pixels differ from %s, see %s\n", name, result.Diff, result.Golden, diff)
+				failed++
+			default:
+				fmt.Printf("PASS %s\n", name)
+				passed++
+			}
+		}
+		if err != nil {
+			fmt.Printf("FAIL %s: %s\n", spec.Name, err)
+			failed++
+		}
+	}
+
+	fmt.Printf("[>] %d passed, %d failed\n", passed, failed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// write the actual frame and the diff image of a failed checkpoint, return the diff file name
+func writeGoldenDiff(out string, name string, result chip8golden.Result) string {
+	os.MkdirAll(out, 0755)
+	diff := filepath.Join(out, name+".diff.png")
+	chip8video.SavePNG(chip8video.FrameImage(result.Frame), filepath.Join(out, name+".actual.png"))
+	chip8video.SavePNG(chip8video.DiffImage(result.Want, result.Frame), diff)
+	return diff
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"chip8golden"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+var updateGoldens = flag.Bool("update", false, "write the framebuffers at the checkpoints as goldens instead of comparing")
+
+// run every spec in testdata/golden, go test -run Golden -update records the goldens again
+func TestGolden(t *testing.T) {
+	specs, err := filepath.Glob(filepath.Join("testdata", "golden", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(specs) == 0 {
+		t.Fatal("no specs in testdata/golden")
+	}
+	for _, fname := range specs {
+		spec, err := chip8golden.LoadSpec(fname)
+		if err != nil {
+			t.Errorf("%s: %s", fname, err)
+			continue
+		}
+		t.Run(spec.Name, func(t *testing.T) {
+			results, err := chip8golden.Run(spec, *updateGoldens)
+			for _, result := range results {
+				switch {
+				case result.Err != nil:
+					t.Errorf("frame %d: %s", result.Checkpoint.Frame, result.Err)
+				case result.Updated:
+					t.Logf("frame %d: wrote %s", result.Checkpoint.Frame, result.Golden)
+				case result.Diff != 0:
+					diff := writeGoldenDiff("golden-out", fmt.Sprintf("%s@%d", spec.Name, result.Checkpoint.Frame), result)
+					t.Errorf("frame %d: %d pixels differ from %s, see %s", result.Checkpoint.Frame, result.Diff, result.Golden, diff)
+				}
+			}
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
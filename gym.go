@@ -0,0 +1,47 @@
+package main
+
+import (
+	"chip8gym"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// serve the environment for training agents over stdio, or with -listen to any number of clients over TCP
+func gym(args []string) int {
+	flags := flag.NewFlagSet("gym", flag.ExitOnError)
+	listen := flags.String("listen", "", "accept clients on this address, like localhost:5555, each with its own environment, instead of using stdio")
+	public := flags.Bool("listen-public", false, "allow -listen on addresses other machines can reach, clients can then read any file of this host")
+	flags.Parse(args)
+
+	if *listen == "" {
+		// the protocol owns stdout
+		out := os.Stdout
+		os.Stdout = os.Stderr
+		if err := chip8gym.Serve(os.Stdin, out); err != nil {
+			fmt.Println("[!] Error when serving the environment: ", err)
+			return 1
+		}
+		return 0
+	}
+
+	listener, err := chip8gym.Listen(*listen, *public)
+	if err != nil {
+		fmt.Println("[!] Error when starting environment server: ", err)
+		return 1
+	}
+	fmt.Println("[>] Waiting for environment clients on", listener.Addr())
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			fmt.Println("[!] Error when accepting environment client: ", err)
+			return 1
+		}
+		go func() {
+			defer conn.Close()
+			if err := chip8gym.Serve(conn, conn); err != nil {
+				fmt.Println("[!] Error when serving environment client: ", err)
+			}
+		}()
+	}
+}
@@ -1,71 +1,288 @@
 package main
 
 import (
+	"chip8api"
+	"chip8callgraph"
+	"chip8cheat"
 	"chip8cpu"
-	"chip8keyboard"
+	"chip8emu"
+	"chip8gdb"
 	"chip8mem"
+	"chip8netplay"
+	"chip8panel"
+	"chip8prof"
+	"chip8romdb"
+	"chip8variant"
 	"chip8video"
+	"context"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 )
 
+// commands selected by the first argument, without one the emulator is started
+var commands = map[string]func(args []string) int{
+	"selftest": selftest,
+	"golden":   golden,
+	"coverage": coverage,
+	"dap":      dap,
+	"analyze":  analyze,
+	"bench":    bench,
+	"gym":      gym,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			os.Exit(command(os.Args[2:]))
+		}
+	}
+	os.Exit(run())
+}
+
+// run the emulator and return the exit status, kept apart from main so that
+// all deferred cleanup has run before the process exits
+func run() int {
 	ROM_fname := flag.String("ROM", "", "name of ROM file to load and start")
 	debug := flag.Bool("debug", false, "dump PC and instr in hex format for each cycle")
-	ticktime := flag.Int("ticktime", 50, "time in ms between ticks")
+	ipf := flag.Int("ipf", chip8emu.DEFAULTIPF, "instructions executed per frame at 60Hz, overrides the ROM database")
+	ticktime := flag.Int("ticktime", 0, "deprecated, use -ipf: time in ms between instructions, rounded to instructions per frame")
+	romdb := flag.String("romdb", "", "directory with the chip-8-database programs.json and platforms.json")
+	profile := flag.String("profile", "", "write a profile report of the executed instructions to this file on exit")
+	pprofOut := flag.String("pprof", "", "write the profile of the executed instructions in pprof format to this file on exit")
+	coverage := flag.String("coverage", "", "write the coverage of the ROM to this file on exit, see the coverage command")
+	callgraph := flag.String("callgraph", "", "write the graph of the subroutine calls in Graphviz DOT format to this file on exit")
+	gdb := flag.String("gdb", "", "serve the GDB remote protocol on this address, like localhost:1234, the ROM starts halted")
+	panel := flag.Bool("panel", false, "show a debug panel with registers, stack, keypad, disassembly and memory next to the game")
+	vip := flag.Bool("vip", false, "run at the speed of the COSMAC VIP by charging every instruction its machine cycles, ignores -ipf")
+	variant := flag.String("variant", "", "run the ROM on a CHIP-8 variant, chip8x or chip8e, instead of what the ROM database says")
+	cache := flag.Bool("cache", false, "run straight runs of instructions through a cache of decoded blocks, not used while profiling, tracing or debugging")
+	cheats := flag.String("cheats", "", "apply the cheats for the ROM from this file, see -ramsearch")
+	ramsearch := flag.Bool("ramsearch", false, "read RAM search and cheat commands from stdin while the ROM runs, type help")
+	host := flag.String("host", "", "wait on this address, like :7777, for a second player to play the ROM over the network")
+	join := flag.String("join", "", "join the netplay of the player hosting on this address, like otherpc:7777, with the same ROM")
+	delay := flag.Int("delay", chip8netplay.DEFAULTDELAY, "frames of input delay for netplay, set by the host")
+	httpAddr := flag.String("http", "", "serve the HTTP/JSON remote control API on this address, like localhost:8080")
+	httpPublic := flag.Bool("http-public", false, "allow -http on addresses other machines can reach, they can then load any file of this host")
+	sys := flag.String("sys", "error", "how 0nnn calls of machine code routines are handled: error or ignore")
 
 	flag.Parse()
 
 	if _, err := os.Stat(*ROM_fname); os.IsNotExist(err) {
 		fmt.Println("[!] Invalid ROM file!")
-		return
+		return 2
 	}
 
 	fmt.Println("[>] Starting emulator")
-	fmt.Println("[>] Loading ROM")
+	if err := chip8video.Init(); err != nil {
+		fmt.Println("[!] Error when initializing SDL: ", err)
+		return 1
+	}
+	defer chip8video.Quit()
 	cpu := chip8cpu.CreateCpu()
 	defer chip8video.CloseVideo(cpu.Video)
+	cpu.Debug = *debug
+	cpu.VIPTiming = *vip
+	if *cache {
+		chip8cpu.EnableBlockCache(cpu)
+	}
+	switch *sys {
+	case "error":
+		cpu.SysMode = chip8cpu.SYS_ERROR
+	case "ignore":
+		cpu.SysMode = chip8cpu.SYS_IGNORE
+	default:
+		fmt.Println("[!] Invalid SYS handling, use error or ignore")
+		return 2
+	}
 
-	err := chip8mem.LoadROM(cpu.Mem, *ROM_fname)
-	if err != nil {
+	var prof *chip8prof.Profile
+	if *profile != "" || *pprofOut != "" {
+		prof = chip8prof.Attach(cpu)
+	}
+	if *coverage != "" {
+		chip8mem.EnableCoverage(cpu.Mem)
+	}
+	var calls *chip8callgraph.CallGraph
+	if *callgraph != "" {
+		calls = chip8callgraph.Track(cpu)
+	}
+
+	emu := chip8emu.CreateEmulator(cpu)
+	if _, ok := chip8variant.Variants[*variant]; *variant != "" && !ok {
+		fmt.Println("[!] Invalid variant, use one of", strings.Join(chip8variant.Names(), ", "))
+		return 2
+	}
+	emu.Variant = *variant
+	emu.Ipf = *ipf
+	ticktimeSet := false
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "ipf":
+			emu.IpfFixed = true
+		case "ticktime":
+			ticktimeSet = true
+		}
+	})
+	if ticktimeSet && !emu.IpfFixed {
+		if *ticktime <= 0 {
+			fmt.Println("[!] Invalid -ticktime, use -ipf")
+			return 2
+		}
+		emu.Ipf = ticktimeIpf(*ticktime)
+		emu.IpfFixed = true
+		fmt.Printf("[!] -ticktime is deprecated, running -ipf %d\n", emu.Ipf)
+	}
+	if *panel {
+		chip8panel.Attach(emu)
+	}
+	if *romdb != "" {
+		db, err := chip8romdb.LoadDatabase(*romdb)
+		if err != nil {
+			fmt.Println("[!] Error when loading ROM database: ", err)
+		}
+		emu.Database = db
+	}
+
+	fmt.Println("[>] Loading ROM")
+	if err := chip8emu.LoadROM(emu, *ROM_fname); err != nil {
 		fmt.Println("[!] Error when loading ROM: ", err)
 	}
-	chip8mem.LoadFonts(cpu.Mem)
+	if emu.Entry != nil {
+		fmt.Printf("[>] ROM identified as %s (%s)\n", emu.Entry.Title, emu.Entry.Platform)
+	}
 
 	fmt.Println("[>] Running video test")
-	// run test by first displaying F manually and then loading B from font
-	chip8video.Test(cpu.Video, 1, []uint8{})
-	chip8video.Render(cpu.Video)
-	time.Sleep(1 * time.Second)
-	sprite := chip8mem.LoadFontSprite(cpu.Mem, 0xB)
-	chip8video.Test(cpu.Video, 2, sprite)
-	chip8video.Render(cpu.Video)
-	time.Sleep(1 * time.Second)
-	chip8video.Clear(cpu.Video)
+	chip8emu.VideoTest(emu)
 	fmt.Println("[>] Video test done")
 
-	fmt.Println("[>] Starting CPU loop")
-	for true {
-		if *debug {
-			chip8cpu.DebugDump(cpu)
+	if *gdb != "" {
+		server, err := chip8gdb.Listen(emu, *gdb)
+		if err != nil {
+			fmt.Println("[!] Error when starting GDB server: ", err)
+			return 1
 		}
-		err := chip8cpu.Tick(cpu)
+		defer chip8gdb.Close(server)
+		go chip8gdb.Serve(server)
+		chip8emu.Pause(emu)
+		fmt.Println("[>] Waiting for GDB on", chip8gdb.Addr(server))
+	}
+
+	if *httpAddr != "" {
+		server, err := chip8api.Listen(emu, *httpAddr, *httpPublic)
 		if err != nil {
-			fmt.Print("[!] CPU has thrown an error: ", err)
-			fmt.Printf(" at PC 0x%X\n", cpu.Mem.PC)
-			break
+			fmt.Println("[!] Error when starting HTTP server: ", err)
+			return 1
 		}
-		if cpu.Video.Dirty {
-			chip8video.Render(cpu.Video)
-			cpu.Video.Dirty = false
+		defer chip8api.Close(server)
+		go chip8api.Serve(server)
+		fmt.Printf("[>] Serving the HTTP API on http://%s\n", chip8api.Addr(server))
+	}
+
+	if *cheats != "" || *ramsearch {
+		file := make(chip8cheat.File)
+		if *cheats != "" {
+			loaded, err := chip8cheat.LoadFile(*cheats)
+			if err != nil {
+				fmt.Println("[!] Error when loading cheats: ", err)
+				return 1
+			}
+			file = loaded
+		}
+		engine := chip8cheat.Attach(emu, file)
+		if *ramsearch {
+			go ramSearch(emu, engine, *cheats, os.Stdin)
+		}
+	}
+
+	fmt.Println("[>] Starting CPU loop")
+	// stop cleanly on ctrl-c and kill as well
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *host != "" || *join != "" {
+		session, err := startNetplay(ctx, emu, *host, *join, *delay)
+		if err != nil {
+			fmt.Println("[!] Error when starting netplay: ", err)
+			return 1
+		}
+		fmt.Printf("[>] Netplay started with %d frames of input delay\n", session.Delay)
+		// an emulator waiting for the other player stops when the session is closed
+		go func() {
+			<-ctx.Done()
+			chip8netplay.Close(session)
+		}()
+		defer chip8netplay.Close(session)
+	}
+
+	code := 0
+	err := chip8emu.Run(emu, ctx)
+	if fault, ok := err.(*chip8emu.Fault); ok {
+		fmt.Print("[!] CPU has thrown an error: ", fault.Err)
+		fmt.Printf(" at PC 0x%X\n", fault.PC)
+		code = 1
+	}
+	if prof != nil {
+		writeProfile(prof, cpu, *profile, *pprofOut, emu.ROM)
+	}
+	if calls != nil {
+		if file, err := os.Create(*callgraph); err != nil {
+			fmt.Println("[!] Error when writing call graph: ", err)
+		} else {
+			chip8callgraph.WriteDOT(calls, file)
+			file.Close()
+			fmt.Println("[>] Call graph written to", *callgraph)
 		}
-		// process the keyboard
-		chip8keyboard.Update(cpu.Keyboard)
-		// time between ticks
-		time.Sleep(time.Duration(*ticktime) * time.Millisecond)
-		//time.Sleep(16666 * time.Microsecond) // T=1/60=16 2/3 ms for 60Hz
 	}
+	if *coverage != "" {
+		if err := chip8mem.SaveCoverage(cpu.Mem.Coverage, *coverage); err != nil {
+			fmt.Println("[!] Error when writing coverage: ", err)
+		} else {
+			fmt.Println("[>] Coverage written to", *coverage)
+		}
+	}
+	fmt.Printf("[>] Emulator stopped (%s) after %d frames at PC 0x%X\n", err, emu.Frames, cpu.Mem.PC)
 	fmt.Println("[>] Emulator done, good bye")
+	return code
+}
+
+// write the profile report and pprof output, either file name may be empty to skip it
+func writeProfile(prof *chip8prof.Profile, cpu *chip8cpu.Cpu, report string, pprof string, rom string) {
+	chip8prof.Finish(prof, cpu)
+	if report != "" {
+		file, err := os.Create(report)
+		if err != nil {
+			fmt.Println("[!] Error when writing profile: ", err)
+		} else {
+			chip8prof.WriteReport(prof, file, 50)
+			file.Close()
+			fmt.Println("[>] Profile report written to", report)
+		}
+	}
+	if pprof != "" {
+		file, err := os.Create(pprof)
+		if err == nil {
+			err = chip8prof.WritePprof(prof, file, rom)
+			file.Close()
+		}
+		if err != nil {
+			fmt.Println("[!] Error when writing pprof profile: ", err)
+		} else {
+			fmt.Println("[>] pprof profile written to", pprof)
+		}
+	}
+}
+
+// instructions per frame for the old -ticktime, at least one as frames are the smallest step now
+func ticktimeIpf(ms int) int {
+	tick := time.Duration(ms) * time.Millisecond
+	ipf := int((chip8emu.FRAMETIME + tick/2) / tick)
+	if ipf < 1 {
+		return 1
+	}
+	return ipf
 }
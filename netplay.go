@@ -0,0 +1,54 @@
+package main
+
+import (
+	"chip8emu"
+	"chip8netplay"
+	"context"
+	"fmt"
+	"net"
+)
+
+// connect to the other player, waiting for it on host or joining it on join, and start netplay with the loaded ROM
+// waiting is given up when ctx is done
+func startNetplay(ctx context.Context, emu *chip8emu.Emulator, host string, join string, delay int) (*chip8netplay.Session, error) {
+	if host != "" {
+		if err := chip8netplay.CheckDelay(delay); err != nil {
+			return nil, err
+		}
+		listener, err := net.Listen("tcp", host)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Println("[>] Waiting for the other player on", listener.Addr())
+		accepted := make(chan struct{})
+		go func() {
+			select {
+			case <-ctx.Done():
+				listener.Close()
+			case <-accepted:
+			}
+		}()
+		conn, err := listener.Accept()
+		close(accepted)
+		listener.Close()
+		if err != nil {
+			return nil, err
+		}
+		session, err := chip8netplay.Host(emu, conn, delay)
+		if err != nil {
+			conn.Close()
+		}
+		return session, err
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", join)
+	if err != nil {
+		return nil, err
+	}
+	session, err := chip8netplay.Join(emu, conn)
+	if err != nil {
+		conn.Close()
+	}
+	return session, err
+}
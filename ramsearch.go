@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bufio"
+	"chip8cheat"
+	"chip8emu"
+	"chip8mem"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const RAMSEARCH_HELP = `commands of the RAM search, numbers may be given as 0x..:
+  new                                     start a search with all addresses from 0x200
+  equal | changed | increased | decreased keep the addresses that changed so since the last filter
+  value V                                 keep the addresses holding V
+  list [N]                                show the first N addresses left, default 20
+  poke ADDR V                             set a byte once
+  freeze ADDR V [name]                    add a cheat setting the byte after every frame
+  patch ADDR V [name]                     add a cheat setting the byte once after loading the ROM
+  remove ADDR                             remove the cheats for an address
+  cheats                                  show the cheats of the ROM
+  save                                    write the cheats to the -cheats file`
+
+// read RAM search commands from r while the emulator runs, they are executed between two frames
+func ramSearch(emu *chip8emu.Emulator, engine *chip8cheat.Engine, cheats string, r io.Reader) {
+	var search *chip8cheat.Search
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		args := strings.Fields(scanner.Text())
+		if len(args) == 0 {
+			continue
+		}
+		var err error
+		done := chip8emu.Do(emu, func() {
+			err = ramSearchCommand(emu, engine, cheats, &search, args)
+		})
+		if done != nil {
+			return
+		}
+		if err != nil {
+			fmt.Println("[!]", err)
+		}
+	}
+}
+
+// execute a single command of the RAM search
+func ramSearchCommand(emu *chip8emu.Emulator, engine *chip8cheat.Engine, cheats string, search **chip8cheat.Search, args []string) error {
+	mem := emu.Cpu.Mem
+	nums := make([]uint16, 0, 2)
+	for _, arg := range args[1:] {
+		n, err := strconv.ParseUint(arg, 0, 16)
+		if err != nil {
+			break
+		}
+		nums = append(nums, uint16(n))
+	}
+	name := ""
+	if len(args) > 3 {
+		name = strings.Join(args[3:], " ")
+	}
+
+	switch cmd := args[0]; cmd {
+	case "new":
+		*search = chip8cheat.StartSearch(mem)
+		fmt.Printf("[>] %d addresses\n", chip8cheat.Count(*search))
+	case "equal", "changed", "increased", "decreased", "value":
+		if *search == nil {
+			return errors.New("No search started, use new")
+		}
+		if cmd == "value" && len(nums) < 1 {
+			return errors.New("usage: value V")
+		}
+		value := uint8(0)
+		if len(nums) > 0 {
+			value = uint8(nums[0])
+		}
+		n := chip8cheat.Filter(*search, mem, chip8cheat.Filters[cmd], value)
+		fmt.Printf("[>] %d addresses left\n", n)
+	case "list":
+		if *search == nil {
+			return errors.New("No search started, use new")
+		}
+		max := 20
+		if len(nums) > 0 {
+			max = int(nums[0])
+		}
+		for _, c := range chip8cheat.Candidates(*search, mem, max) {
+			fmt.Printf("    0x%03X = %3d (was %d)\n", c.Addr, c.Value, c.Previous)
+		}
+	case "poke":
+		if len(nums) < 2 {
+			return errors.New("usage: poke ADDR V")
+		}
+		return chip8mem.SetByte(mem, nums[0], uint8(nums[1]))
+	case "freeze", "patch":
+		if len(nums) < 2 {
+			return errors.New(fmt.Sprintf("usage: %s ADDR V [name]", cmd))
+		}
+		cheat := chip8cheat.Cheat{Name: name, Addr: nums[0], Value: uint8(nums[1]), Freeze: cmd == "freeze"}
+		return chip8cheat.Add(engine, mem, cheat)
+	case "remove":
+		if len(nums) < 1 {
+			return errors.New("usage: remove ADDR")
+		}
+		fmt.Printf("[>] %d cheats removed\n", chip8cheat.Remove(engine, nums[0]))
+	case "cheats":
+		for _, cheat := range chip8cheat.Cheats(engine) {
+			kind := "patch"
+			if cheat.Freeze {
+				kind = "freeze"
+			}
+			fmt.Printf("    %-6s 0x%03X = %3d %s\n", kind, cheat.Addr, cheat.Value, cheat.Name)
+		}
+	case "save":
+		if cheats == "" {
+			return errors.New("No cheat file, start with -cheats")
+		}
+		if err := chip8cheat.SaveFile(engine.File, cheats); err != nil {
+			return err
+		}
+		fmt.Println("[>] Cheats written to", cheats)
+	default:
+		fmt.Println(RAMSEARCH_HELP)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"chip8emu"
+	"chip8golden"
+	"chip8video"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// a conformance ROM to run and the frame it should end on
+type selftestCase struct {
+	Name     string           `json:"name"`
+	ROM      string           `json:"rom"`      // relative to the manifest
+	Frames   int              `json:"frames"`   // frames to run before comparing
+	Ipf      int              `json:"ipf"`      // instructions per frame, default from the platform or DEFAULTIPF
+	Platform string           `json:"platform"` // platform id for the quirks, emulator defaults if empty
+	Poke     map[string]uint8 `json:"poke"`     // bytes to set after loading, keyed by address
+	Hash     string           `json:"hash"`     // expected framebuffer hash, the golden image is used if empty
+	Golden   string           `json:"golden"`   // golden relative to the manifest, .png or ASCII-art .txt, default goldens/<name>.png
+}
+
+type selftestManifest struct {
+	Tests []selftestCase `json:"tests"`
+}
+
+// run the conformance ROMs headless and compare their final frame against the goldens
+func selftest(args []string) int {
+	flags := flag.NewFlagSet("selftest", flag.ExitOnError)
+	manifestFname := flags.String("manifest", filepath.Join("testdata", "selftest", "selftest.json"), "manifest listing the test ROMs")
+	update := flags.Bool("update", false, "write the current frames as golden images instead of comparing")
+	out := flags.String("out", "selftest-out", "directory for the actual and diff images of failed tests")
+	flags.Parse(args)
+
+	data, err := ioutil.ReadFile(*manifestFname)
+	if err != nil {
+		fmt.Println("[!] Error when loading manifest: ", err)
+		return 2
+	}
+	var manifest selftestManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		fmt.Println("[!] Error when loading manifest: ", err)
+		return 2
+	}
+	dir := filepath.Dir(*manifestFname)
+
+	passed, failed, skipped := 0, 0, 0
+	for _, test := range manifest.Tests {
+		rom := filepath.Join(dir, test.ROM)
+		if _, err := os.Stat(rom); os.IsNotExist(err) {
+			fmt.Printf("SKIP %s: ROM %s not found\n", test.Name, rom)
+			skipped++
+			continue
+		}
+		frame, err := runSelftestCase(test, dir)
+		if err != nil {
+			fmt.Printf("FAIL %s: %s\n", test.Name, err)
+			failed++
+			continue
+		}
+
+		golden := test.Golden
+		if golden == "" {
+			golden = filepath.Join("goldens", test.Name+".png")
+		}
+		golden = filepath.Join(dir, golden)
+		if *update {
+			if err := chip8golden.SaveGolden(golden, frame); err != nil {
+				fmt.Printf("FAIL %s: %s\n", test.Name, err)
+				failed++
+				continue
+			}
+			fmt.Printf("NEW  %s: %s (hash %s)\n", test.Name, golden, chip8video.Hash(frame))
+			passed++
+			continue
+		}
+
+		if msg := compareSelftest(test, frame, golden, *out); msg != "" {
+			fmt.Printf("FAIL %s: %s\n", test.Name, msg)
+			failed++
+			continue
+		}
+		fmt.Printf("PASS %s\n", test.Name)
+		passed++
+	}
+
+	fmt.Printf("[>] %d passed, %d failed, %d skipped\n", passed, failed, skipped)
+	if passed+failed == 0 {
+		fmt.Println("[!] No test was run")
+		return 1
+	}
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// run a test ROM on a headless machine and return its final frame
+func runSelftestCase(test selftestCase, dir string) (chip8video.Frame, error) {
+	spec := &chip8golden.Spec{
+		Name:     test.Name,
+		ROM:      test.ROM,
+		Ipf:      test.Ipf,
+		Platform: test.Platform,
+		Poke:     test.Poke,
+		Dir:      dir,
+	}
+	emu, err := chip8golden.CreateMachine(spec)
+	if err != nil {
+		return chip8video.Frame{}, err
+	}
+	if err := chip8emu.RunFrames(emu, test.Frames); err != nil {
+		return chip8video.Frame{}, err
+	}
+	return chip8video.Pixels(emu.Cpu.Video), nil
+}
+
+// compare the frame against the expected hash or golden image, return why it failed or
+// an empty string if it passed, the actual and diff image of a failure are written to out
+func compareSelftest(test selftestCase, frame chip8video.Frame, golden string, out string) string {
+	hash := chip8video.Hash(frame)
+	if test.Hash != "" {
+		if hash != test.Hash {
+			return fmt.Sprintf("hash %s, expected %s", hash, test.Hash)
+		}
+		return ""
+	}
+
+	os.MkdirAll(out, 0755)
+	actual := filepath.Join(out, test.Name+".actual.png")
+	if _, err := os.Stat(golden); os.IsNotExist(err) {
+		// a ROM without an expected frame checks nothing, it fails until its frame was checked and recorded
+		chip8video.SavePNG(chip8video.FrameImage(frame), actual)
+		return fmt.Sprintf("no golden %s and no hash, check %s by eye and record it with -update", golden, actual)
+	}
+	want, err := chip8golden.LoadGolden(golden)
+	if err != nil {
+		return err.Error()
+	}
+	n := chip8video.CountDiff(want, frame)
+	if n == 0 {
+		return ""
+	}
+
+	diff := filepath.Join(out, test.Name+".diff.png")
+	chip8video.SavePNG(chip8video.FrameImage(frame), actual)
+	chip8video.SavePNG(chip8video.DiffImage(want, frame), diff)
+	return fmt.Sprintf("%d pixels differ, see %s", n, diff)
+}
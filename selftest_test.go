@@ -0,0 +1,41 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// run the conformance ROMs of testdata/selftest, the third party ROMs are skipped when missing
+func TestSelftest(t *testing.T) {
+	dir := filepath.Join("testdata", "selftest")
+	data, err := ioutil.ReadFile(filepath.Join(dir, "selftest.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest selftestManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range manifest.Tests {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			if _, err := os.Stat(filepath.Join(dir, test.ROM)); os.IsNotExist(err) {
+				t.Skipf("ROM %s not found", test.ROM)
+			}
+			frame, err := runSelftestCase(test, dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			golden := test.Golden
+			if golden == "" {
+				golden = filepath.Join("goldens", test.Name+".png")
+			}
+			if msg := compareSelftest(test, frame, filepath.Join(dir, golden), "selftest-out"); msg != "" {
+				t.Error(msg)
+			}
+		})
+	}
+}